@@ -0,0 +1,59 @@
+package ciutils
+
+import (
+	"fmt"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// JobOptions configures non-interactive job selection, mirroring the
+// upstream getJobIdInteractive pattern: a predicate narrows down which job
+// (or jobs) in a pipeline are eligible, so callers like `ci trace` don't
+// have to hang on a survey prompt when running in CI or a script.
+type JobOptions struct {
+	// SelectionPredicate, if non-nil, restricts selection to jobs it
+	// returns true for. A nil predicate matches every job.
+	SelectionPredicate func(*gitlab.Job) bool
+	// SelectionPrompt is the message shown when more than one job matches
+	// the predicate and the caller can prompt interactively.
+	SelectionPrompt string
+}
+
+// JobInputs is a pipeline's jobs together with how to pick one of them.
+type JobInputs struct {
+	Jobs    []*gitlab.Job
+	Options JobOptions
+}
+
+// SelectJob narrows Jobs down with Options.SelectionPredicate. Exactly one
+// match is returned directly; zero matches is an error; more than one match
+// is resolved by calling prompt when isTTY is true, and is an error
+// otherwise, so callers never hang waiting on a prompt that can't be
+// answered (e.g. in a `git push` hook or CI script).
+func (in JobInputs) SelectJob(isTTY bool, prompt func(matches []*gitlab.Job, message string) (*gitlab.Job, error)) (*gitlab.Job, error) {
+	matches := in.Jobs
+	if in.Options.SelectionPredicate != nil {
+		matches = nil
+		for _, job := range in.Jobs {
+			if in.Options.SelectionPredicate(job) {
+				matches = append(matches, job)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no jobs matched the given selection")
+	case 1:
+		return matches[0], nil
+	default:
+		if !isTTY {
+			return nil, fmt.Errorf("%d jobs matched the given selection; narrow it down with more specific flags", len(matches))
+		}
+		message := in.Options.SelectionPrompt
+		if message == "" {
+			message = "Select a job:"
+		}
+		return prompt(matches, message)
+	}
+}
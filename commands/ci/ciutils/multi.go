@@ -0,0 +1,49 @@
+package ciutils
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// syncSink serializes access to an underlying Sink so that several
+// goroutines tracing different jobs at once (see TraceRun callers fanning
+// out over multiple jobs) can safely share one output stream.
+type syncSink struct {
+	mu    sync.Mutex
+	inner Sink
+}
+
+// NewSyncSink wraps inner so its Line and Summary methods are safe to call
+// concurrently from multiple goroutines.
+func NewSyncSink(inner Sink) Sink {
+	return &syncSink{inner: inner}
+}
+
+func (s *syncSink) Line(rec LineRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inner.Line(rec)
+}
+
+func (s *syncSink) Summary(rec SummaryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inner.Summary(rec)
+}
+
+var jobColors = []string{
+	"\x1b[36m", // cyan
+	"\x1b[35m", // magenta
+	"\x1b[33m", // yellow
+	"\x1b[32m", // green
+	"\x1b[34m", // blue
+	"\x1b[31m", // red
+}
+
+// jobColor deterministically picks a color for a job name so the same job
+// is always tagged with the same color across a multi-job trace.
+func jobColor(jobName string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(jobName))
+	return jobColors[h.Sum32()%uint32(len(jobColors))]
+}
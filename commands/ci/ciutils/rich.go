@@ -0,0 +1,164 @@
+package ciutils
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// SectionMode controls how the rich Sink displays a finished trace section.
+type SectionMode string
+
+const (
+	SectionsAuto      SectionMode = "auto"
+	SectionsCollapsed SectionMode = "collapsed"
+	SectionsExpanded  SectionMode = "expanded"
+)
+
+var failurePatternRE = regexp.MustCompile(`(?i)(ERROR:|FATAL:|npm ERR!|panic:|Traceback \(most recent call last\))`)
+
+var runnerPreambleRE = regexp.MustCompile(`^Running with gitlab-runner (\S+) \(([0-9a-f]+)\)`)
+
+// richSectionState tracks the section currently being buffered for one job,
+// so richSink can render several jobs' sections independently when it is
+// shared by a multi-job trace (see NewSyncSink).
+type richSectionState struct {
+	name        string
+	buf         []string
+	hot         bool
+	sawPreamble bool
+}
+
+// richSink is the "rich" renderer: by default (SectionsAuto) it collapses
+// sections that finished without a recognized failure pattern into a single
+// "▶ name (3.4s) ✓" line and keeps failing sections fully expanded.
+// SectionsCollapsed/SectionsExpanded override that per-section judgment and
+// apply to every section uniformly. It also condenses the
+// "Running with gitlab-runner ..." preamble into a one-line header and
+// colorizes recognized failure patterns. State is tracked per job name so a
+// shared richSink (see NewSyncSink) can render several jobs at once without
+// their sections bleeding into each other.
+type richSink struct {
+	w        io.Writer
+	sections SectionMode
+	noColor  bool
+
+	state map[string]*richSectionState
+}
+
+// NewRichSink returns a Sink that renders collapsible sections and
+// highlights failures, meant for an interactive (TTY) terminal. noColor
+// leaves out the ANSI color escapes it would otherwise add (--no-color).
+func NewRichSink(w io.Writer, sections SectionMode, noColor bool) Sink {
+	if sections == "" {
+		sections = SectionsAuto
+	}
+	return &richSink{w: w, sections: sections, noColor: noColor, state: make(map[string]*richSectionState)}
+}
+
+// colorize wraps text in the given ANSI escape code, unless noColor is set.
+func (s *richSink) colorize(code, text string) string {
+	if s.noColor {
+		return text
+	}
+	return code + text + "\x1b[0m"
+}
+
+func (s *richSink) stateFor(jobName string) *richSectionState {
+	st, ok := s.state[jobName]
+	if !ok {
+		st = &richSectionState{}
+		s.state[jobName] = st
+	}
+	return st
+}
+
+// tag renders the "[job-name] " prefix used when more than one job is being
+// traced, mirroring textSink's job tagging.
+func (s *richSink) tag(jobName string) string {
+	if jobName == "" {
+		return ""
+	}
+	return s.colorize(jobColor(jobName), fmt.Sprintf("[%s]", jobName)) + " "
+}
+
+func (s *richSink) Line(rec LineRecord) error {
+	st := s.stateFor(rec.JobName)
+
+	if !st.sawPreamble {
+		if m := runnerPreambleRE.FindStringSubmatch(rec.Text); m != nil {
+			st.sawPreamble = true
+			_, err := fmt.Fprintf(s.w, "%s%s\n", s.tag(rec.JobName), s.colorize("\x1b[90m", fmt.Sprintf("▶ runner %s (%s)", m[1], m[2])))
+			return err
+		}
+	}
+
+	switch {
+	case rec.SectionEnd:
+		return s.closeSection(rec, st)
+	case rec.Section != "":
+		if st.name != rec.Section {
+			st.name = rec.Section
+			st.buf = nil
+			st.hot = false
+		}
+		if rec.Text != "" {
+			st.buf = append(st.buf, rec.Text)
+			if failurePatternRE.MatchString(rec.Text) {
+				st.hot = true
+			}
+		}
+		return nil
+	default:
+		_, err := fmt.Fprintln(s.w, s.tag(rec.JobName)+s.colorizeFailure(rec.Text))
+		return err
+	}
+}
+
+func (s *richSink) closeSection(rec LineRecord, st *richSectionState) error {
+	expand := st.hot
+	switch s.sections {
+	case SectionsCollapsed:
+		expand = false
+	case SectionsExpanded:
+		expand = true
+	}
+
+	mark := s.colorize("\x1b[32m", "✓")
+	if st.hot {
+		mark = s.colorize("\x1b[31m", "✗")
+	}
+
+	if _, err := fmt.Fprintf(s.w, "%s▶ %s (%.1fs) %s\n", s.tag(rec.JobName), rec.Section, rec.SectionDuration.Seconds(), mark); err != nil {
+		return err
+	}
+
+	if expand {
+		for _, line := range st.buf {
+			if _, err := fmt.Fprintln(s.w, s.tag(rec.JobName)+s.colorizeFailure(line)); err != nil {
+				return err
+			}
+		}
+	}
+
+	st.name = ""
+	st.buf = nil
+	st.hot = false
+	return nil
+}
+
+func (s *richSink) Summary(rec SummaryRecord) error {
+	_, err := fmt.Fprintf(s.w, "\nJob %s: %s in %s (exit code %d, runner %s)\n",
+		rec.JobName, rec.Status, rec.Duration.Round(time.Millisecond), rec.ExitCode, rec.Runner)
+	return err
+}
+
+// colorizeFailure wraps a line in red if it matches a recognized failure
+// pattern, so errors stand out even outside a collapsed section.
+func (s *richSink) colorizeFailure(text string) string {
+	if failurePatternRE.MatchString(text) {
+		return s.colorize("\x1b[31m", text)
+	}
+	return text
+}
@@ -0,0 +1,76 @@
+package ciutils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRichSink_CollapsesSuccessfulSection(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewRichSink(&buf, SectionsAuto, true)
+
+	_ = sink.Line(LineRecord{Section: "build", Text: "compiling..."})
+	_ = sink.Line(LineRecord{Section: "build", SectionEnd: true, SectionDuration: 3400 * time.Millisecond})
+
+	out := buf.String()
+	if strings.Contains(out, "compiling...") {
+		t.Errorf("expected a successful section to collapse, got the raw line in output: %q", out)
+	}
+	if !strings.Contains(out, "▶ build (3.4s) ✓") {
+		t.Errorf("expected a collapsed summary line, got %q", out)
+	}
+}
+
+func TestRichSink_ExpandsFailingSection(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewRichSink(&buf, SectionsAuto, true)
+
+	_ = sink.Line(LineRecord{Section: "test", Text: "running tests"})
+	_ = sink.Line(LineRecord{Section: "test", Text: "ERROR: assertion failed"})
+	_ = sink.Line(LineRecord{Section: "test", SectionEnd: true, SectionDuration: 500 * time.Millisecond})
+
+	out := buf.String()
+	if !strings.Contains(out, "ERROR: assertion failed") {
+		t.Errorf("expected a failing section to stay expanded, got %q", out)
+	}
+	if !strings.Contains(out, "✗") {
+		t.Errorf("expected the failure mark, got %q", out)
+	}
+}
+
+// TestRichSink_SameSecondSectionStillCloses is a regression test: GitLab's
+// section markers only carry unix-second timestamps, so a section that
+// starts and ends within the same second has SectionDuration == 0. The rich
+// sink must still treat that as a closed section (via LineRecord.SectionEnd)
+// instead of dropping its buffered lines on the next section.
+func TestRichSink_SameSecondSectionStillCloses(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewRichSink(&buf, SectionsAuto, true)
+
+	_ = sink.Line(LineRecord{Section: "quick", Text: "ERROR: boom"})
+	_ = sink.Line(LineRecord{Section: "quick", SectionEnd: true, SectionDuration: 0})
+	_ = sink.Line(LineRecord{Section: "next", Text: "unrelated output"})
+
+	out := buf.String()
+	if !strings.Contains(out, "▶ quick (0.0s) ✗") {
+		t.Errorf("expected the zero-duration section to still render its close line, got %q", out)
+	}
+	if !strings.Contains(out, "ERROR: boom") {
+		t.Errorf("expected the failing zero-duration section to render its buffered lines, got %q", out)
+	}
+}
+
+func TestRichSink_SectionsCollapsedForcesCollapse(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewRichSink(&buf, SectionsCollapsed, true)
+
+	_ = sink.Line(LineRecord{Section: "test", Text: "ERROR: assertion failed"})
+	_ = sink.Line(LineRecord{Section: "test", SectionEnd: true, SectionDuration: time.Second})
+
+	out := buf.String()
+	if strings.Contains(out, "ERROR: assertion failed") {
+		t.Errorf("--sections=collapsed should hide buffered lines even for a failing section, got %q", out)
+	}
+}
@@ -0,0 +1,159 @@
+package ciutils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// pollInterval is how often RunTrace asks GitLab for new trace bytes and for
+// an updated job status while the job is still running. It's a var, not a
+// const, so tests can shorten it instead of running at production speed.
+var pollInterval = time.Second * 2
+
+// sectionRE matches GitLab's section markers, e.g.
+//
+//	section_start:1600000000:build_section\r\x1b[0Kheader text
+//	section_end:1600000000:build_section\r\x1b[0K
+var sectionRE = regexp.MustCompile(`^section_(start|end):(\d+):([^\r]+)\r\x1b\[0K(.*)$`)
+
+// RunTrace streams the trace of job to sink, polling for new output until
+// the job reaches a terminal state. It replaces the previous behaviour of
+// copying the raw trace bytes straight to an io.Writer, so that callers
+// (text, JSON/NDJSON output, or future renderers) can consume structured
+// records instead. It returns the job's final SummaryRecord so callers
+// tracing several jobs at once can tell which of them failed.
+func RunTrace(ctx context.Context, apiClient *gitlab.Client, sink Sink, pid interface{}, job *gitlab.Job, jobName string) (SummaryRecord, error) {
+	var (
+		offset     int
+		sectionTop string
+		sectionAt  time.Time
+	)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		select {
+		case <-ctx.Done():
+			return SummaryRecord{}, ctx.Err()
+		default:
+		}
+
+		trace, _, err := apiClient.Jobs.GetTraceFile(pid, job.ID, gitlab.WithContext(ctx))
+		if err != nil {
+			return SummaryRecord{}, err
+		}
+
+		buf := new(bytes.Buffer)
+		if _, err := buf.ReadFrom(trace); err != nil {
+			return SummaryRecord{}, err
+		}
+
+		chunk := buf.String()
+		if len(chunk) < offset {
+			// the trace was reset (rare, but GitLab does this on retry)
+			offset = 0
+		}
+		newBytes := chunk[offset:]
+		offset = len(chunk)
+
+		scanner := bufio.NewScanner(strings.NewReader(newBytes))
+		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			var closedSection string
+			var sectionDuration time.Duration
+			var sawEnd bool
+
+			if m := sectionRE.FindStringSubmatch(line); m != nil {
+				switch m[1] {
+				case "start":
+					sectionTop = m[3]
+					sectionAt = time.Unix(parseUnix(m[2]), 0)
+					line = StripANSI(m[4])
+				case "end":
+					closedSection = sectionTop
+					sectionDuration = time.Unix(parseUnix(m[2]), 0).Sub(sectionAt)
+					sawEnd = true
+					sectionTop = ""
+					line = StripANSI(m[4])
+				}
+			} else if line == "" {
+				continue
+			}
+
+			rec := LineRecord{
+				JobName: jobName,
+				Time:    time.Now(),
+				Text:    StripANSI(line),
+				Section: sectionTop,
+			}
+			if sawEnd {
+				rec.Section = closedSection
+				rec.SectionEnd = true
+				rec.SectionDuration = sectionDuration
+			}
+
+			if err := sink.Line(rec); err != nil {
+				return SummaryRecord{}, err
+			}
+		}
+
+		job, _, err = apiClient.Jobs.GetJob(pid, job.ID, gitlab.WithContext(ctx))
+		if err != nil {
+			return SummaryRecord{}, err
+		}
+
+		if isTerminalStatus(job.Status) {
+			break
+		}
+	}
+
+	var exitCode int
+	if job.Status != "success" {
+		exitCode = 1
+	}
+
+	summary := SummaryRecord{
+		JobName:  jobName,
+		Status:   job.Status,
+		Duration: time.Duration(job.Duration * float64(time.Second)),
+		Runner:   runnerDescription(job),
+		ExitCode: exitCode,
+	}
+
+	return summary, sink.Summary(summary)
+}
+
+func isTerminalStatus(status string) bool {
+	switch status {
+	case "success", "failed", "canceled", "skipped":
+		return true
+	default:
+		return false
+	}
+}
+
+func runnerDescription(job *gitlab.Job) string {
+	if job.Runner == nil {
+		return ""
+	}
+	if job.Runner.Description != "" {
+		return job.Runner.Description
+	}
+	return fmt.Sprintf("#%d", job.Runner.ID)
+}
+
+func parseUnix(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
@@ -0,0 +1,111 @@
+package ciutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// fakeSink records every LineRecord/SummaryRecord it receives, so tests can
+// assert on RunTrace's section-marker parsing without a real renderer.
+type fakeSink struct {
+	lines   []LineRecord
+	summary SummaryRecord
+}
+
+func (f *fakeSink) Line(rec LineRecord) error {
+	f.lines = append(f.lines, rec)
+	return nil
+}
+
+func (f *fakeSink) Summary(rec SummaryRecord) error {
+	f.summary = rec
+	return nil
+}
+
+// newTraceTestClient spins up a fake GitLab API returning trace and job
+// status fixtures for project 1, job 42.
+func newTraceTestClient(t *testing.T, trace, jobJSON string) *gitlab.Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/1/jobs/42/trace", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, trace)
+	})
+	mux.HandleFunc("/api/v4/projects/1/jobs/42", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, jobJSON)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	client, err := gitlab.NewClient("", gitlab.WithBaseURL(ts.URL))
+	if err != nil {
+		t.Fatalf("gitlab.NewClient: %v", err)
+	}
+	return client
+}
+
+// TestRunTrace_SectionParsing covers the section_start/section_end scanner:
+// a section that starts and ends within the same unix second (SectionDuration
+// == 0, which GitLab produces for most short steps) must still be reported as
+// a closed section via LineRecord.SectionEnd, not be indistinguishable from a
+// line that is merely inside an open section.
+func TestRunTrace_SectionParsing(t *testing.T) {
+	orig := pollInterval
+	pollInterval = time.Millisecond
+	defer func() { pollInterval = orig }()
+
+	trace := "section_start:1700000000:quick_section\r\x1b[0Krunning quick step\n" +
+		"line inside section\n" +
+		"section_end:1700000000:quick_section\r\x1b[0K\n" +
+		"line outside any section\n"
+
+	client := newTraceTestClient(t, trace, `{"id":42,"status":"success","duration":1.5}`)
+
+	sink := &fakeSink{}
+	job := &gitlab.Job{ID: 42}
+
+	summary, err := RunTrace(context.Background(), client, sink, 1, job, "")
+	if err != nil {
+		t.Fatalf("RunTrace: %v", err)
+	}
+
+	var end *LineRecord
+	for i := range sink.lines {
+		if sink.lines[i].SectionEnd {
+			end = &sink.lines[i]
+		}
+	}
+	if end == nil {
+		t.Fatalf("expected a SectionEnd record, got none in %+v", sink.lines)
+	}
+	if end.Section != "quick_section" {
+		t.Errorf("Section = %q, want %q", end.Section, "quick_section")
+	}
+	if end.SectionDuration != 0 {
+		t.Errorf("SectionDuration = %v, want 0 for a same-second start/end", end.SectionDuration)
+	}
+
+	var outside *LineRecord
+	for i := range sink.lines {
+		if sink.lines[i].Text == "line outside any section" {
+			outside = &sink.lines[i]
+		}
+	}
+	if outside == nil {
+		t.Fatalf("expected the line outside any section to reach the sink, got %+v", sink.lines)
+	}
+	if outside.Section != "" {
+		t.Errorf("Section = %q for a line outside any section, want empty", outside.Section)
+	}
+
+	if summary.Status != "success" {
+		t.Errorf("summary.Status = %q, want success", summary.Status)
+	}
+}
@@ -0,0 +1,194 @@
+package ciutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// Format selects how a Sink renders trace lines.
+type Format string
+
+const (
+	FormatText   Format = "text"
+	FormatRich   Format = "rich"
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+)
+
+// LineRecord is a single line of job trace output, with GitLab's section
+// markers and ANSI escape sequences already stripped out.
+type LineRecord struct {
+	JobName         string
+	Time            time.Time
+	Text            string
+	Section         string
+	SectionEnd      bool
+	SectionDuration time.Duration
+}
+
+// lineRecordJSON is the wire representation of a LineRecord: time.Duration
+// has no custom MarshalJSON, so encoding it directly would serialize raw
+// nanoseconds under a field name that promises milliseconds.
+type lineRecordJSON struct {
+	JobName           string    `json:"job_name,omitempty"`
+	Time              time.Time `json:"time"`
+	Text              string    `json:"text"`
+	Section           string    `json:"section,omitempty"`
+	SectionEnd        bool      `json:"section_end,omitempty"`
+	SectionDurationMS int64     `json:"section_duration_ms,omitempty"`
+}
+
+// MarshalJSON renders SectionDuration in milliseconds, matching its
+// section_duration_ms field name.
+func (r LineRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(lineRecordJSON{
+		JobName:           r.JobName,
+		Time:              r.Time,
+		Text:              r.Text,
+		Section:           r.Section,
+		SectionEnd:        r.SectionEnd,
+		SectionDurationMS: r.SectionDuration.Milliseconds(),
+	})
+}
+
+// SummaryRecord is emitted once, after a traced job has finished.
+type SummaryRecord struct {
+	JobName  string
+	Status   string
+	Duration time.Duration
+	Runner   string
+	ExitCode int
+}
+
+// summaryRecordJSON is the wire representation of a SummaryRecord; see
+// lineRecordJSON for why Duration isn't encoded directly.
+type summaryRecordJSON struct {
+	JobName    string `json:"job_name,omitempty"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Runner     string `json:"runner,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+}
+
+// MarshalJSON renders Duration in milliseconds, matching its duration_ms
+// field name.
+func (r SummaryRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(summaryRecordJSON{
+		JobName:    r.JobName,
+		Status:     r.Status,
+		DurationMS: r.Duration.Milliseconds(),
+		Runner:     r.Runner,
+		ExitCode:   r.ExitCode,
+	})
+}
+
+// Sink receives structured trace output as a job log streams in. It lets
+// RunTrace stay agnostic of whether the caller wants a human-readable
+// stream, a log-aggregator-friendly NDJSON stream, or something else
+// entirely.
+type Sink interface {
+	Line(rec LineRecord) error
+	Summary(rec SummaryRecord) error
+}
+
+var ansiRE = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+// StripANSI removes terminal escape sequences from s.
+func StripANSI(s string) string {
+	return ansiRE.ReplaceAllString(s, "")
+}
+
+// NewSink builds the Sink for the given format, writing to w. noColor is only
+// honoured by the text and rich sinks; sections is only honoured by the rich
+// sink.
+func NewSink(format Format, w io.Writer, noColor bool, sections SectionMode) (Sink, error) {
+	switch format {
+	case "", FormatText:
+		return &textSink{w: w, noColor: noColor}, nil
+	case FormatRich:
+		return NewRichSink(w, sections, noColor), nil
+	case FormatJSON:
+		return &jsonSink{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonSink{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unknown trace format %q", format)
+	}
+}
+
+type textSink struct {
+	w       io.Writer
+	noColor bool
+}
+
+func (s *textSink) Line(rec LineRecord) error {
+	if rec.JobName == "" {
+		_, err := fmt.Fprintln(s.w, rec.Text)
+		return err
+	}
+
+	tag := fmt.Sprintf("[%s]", rec.JobName)
+	if !s.noColor {
+		tag = jobColor(rec.JobName) + tag + "\x1b[0m"
+	}
+	_, err := fmt.Fprintf(s.w, "%s %s\n", tag, rec.Text)
+	return err
+}
+
+func (s *textSink) Summary(rec SummaryRecord) error {
+	_, err := fmt.Fprintf(s.w, "\nJob %s: %s in %s (exit code %d, runner %s)\n",
+		rec.JobName, rec.Status, rec.Duration.Round(time.Millisecond), rec.ExitCode, rec.Runner)
+	return err
+}
+
+// ndjsonSink writes one JSON object per line, suitable for piping into log
+// aggregators as the trace streams in.
+type ndjsonSink struct {
+	w io.Writer
+}
+
+func (s *ndjsonSink) Line(rec LineRecord) error {
+	return writeJSONLine(s.w, rec)
+}
+
+func (s *ndjsonSink) Summary(rec SummaryRecord) error {
+	return writeJSONLine(s.w, rec)
+}
+
+func writeJSONLine(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// jsonSink buffers every record and emits a single JSON document on Summary,
+// for callers that would rather parse one object than a stream of lines.
+type jsonSink struct {
+	w     io.Writer
+	lines []LineRecord
+}
+
+func (s *jsonSink) Line(rec LineRecord) error {
+	s.lines = append(s.lines, rec)
+	return nil
+}
+
+func (s *jsonSink) Summary(rec SummaryRecord) error {
+	doc := struct {
+		Lines   []LineRecord  `json:"lines"`
+		Summary SummaryRecord `json:"summary"`
+	}{Lines: s.lines, Summary: rec}
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(s.w, string(b))
+	return err
+}
@@ -0,0 +1,63 @@
+package ciutils
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestLineRecord_MarshalJSON_DurationIsMilliseconds guards against
+// time.Duration's nanosecond default JSON encoding leaking out under a field
+// name that promises milliseconds.
+func TestLineRecord_MarshalJSON_DurationIsMilliseconds(t *testing.T) {
+	rec := LineRecord{
+		Section:         "build",
+		SectionEnd:      true,
+		SectionDuration: 3400 * time.Millisecond,
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	durationMS, ok := got["section_duration_ms"].(float64)
+	if !ok {
+		t.Fatalf("section_duration_ms missing or not a number in %s", b)
+	}
+	if durationMS != 3400 {
+		t.Errorf("section_duration_ms = %v, want 3400", durationMS)
+	}
+}
+
+func TestSummaryRecord_MarshalJSON_DurationIsMilliseconds(t *testing.T) {
+	rec := SummaryRecord{
+		JobName:  "build",
+		Status:   "success",
+		Duration: 90 * time.Second,
+		ExitCode: 0,
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	durationMS, ok := got["duration_ms"].(float64)
+	if !ok {
+		t.Fatalf("duration_ms missing or not a number in %s", b)
+	}
+	if durationMS != 90000 {
+		t.Errorf("duration_ms = %v, want 90000", durationMS)
+	}
+}
@@ -0,0 +1,35 @@
+package ciutils
+
+// teeSink forwards every record to each of its sinks, used by `ci trace
+// --output`/`--bundle` to write the live trace to a file alongside the
+// user-facing stream.
+type teeSink struct {
+	sinks []Sink
+}
+
+// NewTeeSink returns a Sink that forwards every Line and Summary call to
+// each of sinks, in order, returning the first error encountered (but still
+// calling the rest, so a broken file write doesn't silence the terminal).
+func NewTeeSink(sinks ...Sink) Sink {
+	return &teeSink{sinks: sinks}
+}
+
+func (t *teeSink) Line(rec LineRecord) error {
+	var firstErr error
+	for _, s := range t.sinks {
+		if err := s.Line(rec); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t *teeSink) Summary(rec SummaryRecord) error {
+	var firstErr error
+	for _, s := range t.sinks {
+		if err := s.Summary(rec); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
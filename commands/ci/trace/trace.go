@@ -2,9 +2,17 @@ package trace
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/profclems/glab/pkg/iostreams"
 
@@ -24,10 +32,36 @@ import (
 	"github.com/xanzy/go-gitlab"
 )
 
+// defaultTraceConcurrency bounds how many jobs `ci trace` streams at once
+// when tracing more than one job (--all, --stage, --match, or several job
+// names), so a large pipeline doesn't open dozens of simultaneous log polls.
+const defaultTraceConcurrency = 5
+
 type TraceOpts struct {
 	Branch string
 	JobID  int
 
+	JobNames    []string
+	All         bool
+	Stage       string
+	Match       string
+	Pipeline    int
+	Concurrency int
+
+	Follow       bool
+	PollInterval time.Duration
+	MaxWait      time.Duration
+
+	Output string
+	Bundle string
+
+	JobName string
+	Status  string
+
+	Format   string
+	Sections string
+	NoColor  bool
+
 	BaseRepo   func() (glrepo.Interface, error)
 	HTTPClient func() (*gitlab.Client, error)
 	IO         *iostreams.IOStreams
@@ -46,6 +80,15 @@ func NewCmdTrace(f *cmdutils.Factory, runE func(traceOpts *TraceOpts) error) *co
 
 	$ glab ci trace 224356863
 	#=> trace job with id 224356863
+
+	$ glab ci trace --all
+	#=> trace every job in the latest pipeline at once
+
+	$ glab ci trace --stage=test
+	#=> trace every job in the "test" stage at once
+
+	$ glab ci trace build-amd64 build-arm64
+	#=> trace these two jobs concurrently
 	`),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
@@ -58,8 +101,14 @@ func NewCmdTrace(f *cmdutils.Factory, runE func(traceOpts *TraceOpts) error) *co
 			opts.BaseRepo = f.BaseRepo
 			opts.HTTPClient = f.HttpClient
 
-			if len(args) != 0 {
-				opts.JobID = utils.StringToInt(args[0])
+			if len(args) == 1 {
+				if id := utils.StringToInt(args[0]); id > 0 {
+					opts.JobID = id
+				} else {
+					opts.JobNames = args
+				}
+			} else if len(args) > 1 {
+				opts.JobNames = args
 			}
 			if opts.Branch == "" {
 				opts.Branch, err = git.CurrentBranch()
@@ -67,6 +116,29 @@ func NewCmdTrace(f *cmdutils.Factory, runE func(traceOpts *TraceOpts) error) *co
 					return err
 				}
 			}
+			if cmd.Flags().Changed("format") {
+				switch opts.Format {
+				case string(ciutils.FormatText), string(ciutils.FormatRich), string(ciutils.FormatJSON), string(ciutils.FormatNDJSON):
+				default:
+					return fmt.Errorf("unsupported --format %q: must be one of text, rich, json, ndjson", opts.Format)
+				}
+			} else if opts.IO.IsStdoutTTY() {
+				// rich is nicer on an interactive terminal, but would just
+				// add noise to a pipe or log file, so it's opt-out rather
+				// than the flag default.
+				opts.Format = string(ciutils.FormatRich)
+			}
+
+			switch opts.Sections {
+			case "", string(ciutils.SectionsAuto), string(ciutils.SectionsCollapsed), string(ciutils.SectionsExpanded):
+			default:
+				return fmt.Errorf("unsupported --sections %q: must be one of auto, collapsed, expanded", opts.Sections)
+			}
+
+			if err := validateMultiTraceFlags(opts); err != nil {
+				return err
+			}
+
 			if runE != nil {
 				return runE(opts)
 			}
@@ -75,10 +147,138 @@ func NewCmdTrace(f *cmdutils.Factory, runE func(traceOpts *TraceOpts) error) *co
 	}
 
 	pipelineCITraceCmd.Flags().StringVarP(&opts.Branch, "branch", "b", "", "Check pipeline status for a branch. (Default is the current branch)")
+	pipelineCITraceCmd.Flags().StringVar(&opts.Format, "format", string(ciutils.FormatText), "Output format: text, rich, json, ndjson (default: rich on a TTY, text otherwise)")
+	pipelineCITraceCmd.Flags().StringVar(&opts.Sections, "sections", "", "For the rich format, how to render finished sections: auto, collapsed, expanded (default: auto)")
+	pipelineCITraceCmd.Flags().BoolVar(&opts.NoColor, "no-color", false, "Disable color output in text and rich formats")
+	pipelineCITraceCmd.Flags().BoolVar(&opts.All, "all", false, "Trace every job in the pipeline at once")
+	pipelineCITraceCmd.Flags().StringVar(&opts.Stage, "stage", "", "Trace every job in this stage at once")
+	pipelineCITraceCmd.Flags().StringVar(&opts.Match, "match", "", "Trace every job whose name matches this regular expression")
+	pipelineCITraceCmd.Flags().IntVar(&opts.Pipeline, "pipeline", 0, "With --all/--stage/--match or job names, trace jobs from this pipeline instead of the latest one on the branch")
+	pipelineCITraceCmd.Flags().IntVar(&opts.Concurrency, "concurrency", defaultTraceConcurrency, "Maximum number of jobs to trace at once")
+	pipelineCITraceCmd.Flags().BoolVarP(&opts.Follow, "follow", "f", false, "Keep running, automatically tracing the equivalent job in each new pipeline pushed to the branch")
+	pipelineCITraceCmd.Flags().DurationVar(&opts.PollInterval, "poll-interval", 5*time.Second, "How often to check for a new pipeline when using --follow")
+	pipelineCITraceCmd.Flags().DurationVar(&opts.MaxWait, "max-wait", 0, "Give up waiting for a new pipeline after this long when using --follow (0 waits forever)")
+	pipelineCITraceCmd.Flags().StringVar(&opts.Output, "output", "", "Tee the live trace to this file in addition to stdout")
+	pipelineCITraceCmd.Flags().StringVar(&opts.Bundle, "bundle", "", "Write a reproducible failure report (trace, job metadata, CI config, artifacts) to this directory")
+	pipelineCITraceCmd.Flags().StringVar(&opts.JobName, "job-name", "", "Trace the job with this exact name, skipping the interactive prompt")
+	pipelineCITraceCmd.Flags().StringVar(&opts.Status, "status", "", "Trace a job with this status (e.g. failed, running), skipping the interactive prompt")
 	return pipelineCITraceCmd
 }
 
+// jobSelectionPredicate builds the ciutils.JobOptions predicate for
+// --job-name/--status, or nil if neither was given, in which case TraceRun
+// falls back to the interactive prompt.
+func jobSelectionPredicate(opts *TraceOpts) func(*gitlab.Job) bool {
+	if opts.JobName == "" && opts.Status == "" {
+		return nil
+	}
+	return func(job *gitlab.Job) bool {
+		if opts.JobName != "" && job.Name != opts.JobName {
+			return false
+		}
+		if opts.Status != "" && job.Status != opts.Status {
+			return false
+		}
+		return true
+	}
+}
+
+// newOutputSink builds the Sink that TraceRun writes to: primary is the
+// user-facing stream (text, rich, JSON, or NDJSON on stdout); if --output
+// or --bundle is set, a second, uncolored copy of the trace in the same
+// format (with text in place of rich) is teed into outputPath. The caller must close the returned io.Closer (nil
+// if no file was opened) once tracing is done.
+func newOutputSink(opts *TraceOpts, outputPath string) (ciutils.Sink, io.Closer, error) {
+	sections := ciutils.SectionMode(opts.Sections)
+
+	primary, err := ciutils.NewSink(ciutils.Format(opts.Format), opts.IO.StdOut, opts.NoColor, sections)
+	if err != nil {
+		return nil, nil, err
+	}
+	if outputPath == "" {
+		return primary, nil, nil
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// rich's collapsed sections and color are for an interactive terminal;
+	// the file copy of a rich trace is written in the plain-text format.
+	fileFormat := ciutils.Format(opts.Format)
+	if fileFormat == ciutils.FormatRich {
+		fileFormat = ciutils.FormatText
+	}
+
+	fileSink, err := ciutils.NewSink(fileFormat, f, true, sections)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return ciutils.NewTeeSink(primary, fileSink), f, nil
+}
+
+// writeBundleExtras fills in the rest of a --bundle directory (job.json,
+// the effective .gitlab-ci.yml, and any job artifacts) once trace.log has
+// already been written by newOutputSink.
+func writeBundleExtras(apiClient *gitlab.Client, repo glrepo.Interface, opts *TraceOpts, job *gitlab.Job) error {
+	meta, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(opts.Bundle, "job.json"), meta, 0o644); err != nil {
+		return err
+	}
+
+	if cfg, _, err := apiClient.RepositoryFiles.GetRawFile(repo.FullName(), ".gitlab-ci.yml", &gitlab.GetRawFileOptions{Ref: gitlab.String(job.Pipeline.SHA)}); err == nil {
+		_ = os.WriteFile(filepath.Join(opts.Bundle, ".gitlab-ci.yml"), cfg, 0o644)
+	}
+
+	if artifacts, _, err := api.DownloadArtifactJob(apiClient, job.ID, repo.FullName()); err == nil {
+		if f, ferr := os.Create(filepath.Join(opts.Bundle, "artifacts.zip")); ferr == nil {
+			_, _ = io.Copy(f, artifacts)
+			f.Close()
+		}
+	}
+
+	return nil
+}
+
+// isMultiTrace reports whether opts selects jobs by --all, --stage, --match
+// or job name, in which case TraceRun fans out over every matching job
+// instead of tracing (or prompting for) a single job.
+func (opts *TraceOpts) isMultiTrace() bool {
+	return opts.All || opts.Stage != "" || opts.Match != "" || len(opts.JobNames) > 0
+}
+
+// validateMultiTraceFlags rejects flag combinations that multiTraceRun can't
+// honor: --bundle and --follow only run their post-trace logic in TraceRun's
+// single-job path, and --format=json's jsonSink buffers one shared document
+// that concurrent jobs would corrupt (each finishing job emits another copy
+// of the document, holding every job's lines buffered so far but only its
+// own SummaryRecord). --format=ndjson streams one
+// record per line and is safe for multi-job tracing.
+func validateMultiTraceFlags(opts *TraceOpts) error {
+	if !opts.isMultiTrace() {
+		return nil
+	}
+	switch {
+	case opts.Bundle != "":
+		return fmt.Errorf("--bundle isn't supported together with --all/--stage/--match/multiple job names; run it once per job instead")
+	case opts.Follow:
+		return fmt.Errorf("--follow isn't supported together with --all/--stage/--match/multiple job names; run it once per job instead")
+	case opts.Format == string(ciutils.FormatJSON):
+		return fmt.Errorf("--format=json isn't supported together with --all/--stage/--match/multiple job names (each job would emit its own overlapping copy of the shared document); use --format=ndjson instead")
+	}
+	return nil
+}
+
 func TraceRun(opts *TraceOpts) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
 	apiClient, err := opts.HTTPClient()
 	if err != nil {
 		return err
@@ -89,6 +289,10 @@ func TraceRun(opts *TraceOpts) error {
 		return err
 	}
 
+	if opts.isMultiTrace() {
+		return multiTraceRun(ctx, opts, apiClient, repo)
+	}
+
 	if opts.JobID < 1 {
 		fmt.Fprintf(opts.IO.StdOut, "\nSearching for latest pipeline on %s...\n", opts.Branch)
 
@@ -104,71 +308,342 @@ func TraceRun(opts *TraceOpts) error {
 			return err
 		}
 
-		var jobOptions []string
-		var selectedJob string
+		if predicate := jobSelectionPredicate(opts); predicate != nil {
+			inputs := ciutils.JobInputs{
+				Jobs: jobs,
+				Options: ciutils.JobOptions{
+					SelectionPredicate: predicate,
+					SelectionPrompt:    "Select pipeline job to trace:",
+				},
+			}
+
+			job, err := inputs.SelectJob(opts.IO.IsStdinTTY(), promptSelectJob)
+			if err != nil {
+				return err
+			}
+
+			opts.JobID = job.ID
+		} else if err := selectJobInteractively(opts, apiClient, repo, pipeline, jobs); err != nil {
+			return err
+		}
 
-		for _, job := range jobs {
-			jobOptions = append(jobOptions, fmt.Sprintf("%s (%d) - %s", job.Name, job.ID, job.Status))
+		if opts.JobID < 1 {
+			return nil
 		}
+	}
+
+	job, err := api.GetPipelineJob(apiClient, opts.JobID, repo.FullName())
+	if err != nil {
+		return err
+	}
 
-		promptOpts := &survey.Select{
-			Message: "Select pipeline job to trace:",
-			Options: jobOptions,
+	outputPath := opts.Output
+	if opts.Bundle != "" {
+		if err := os.MkdirAll(opts.Bundle, 0o755); err != nil {
+			return err
 		}
+		outputPath = filepath.Join(opts.Bundle, "trace.log")
+	}
+
+	sink, outFile, err := newOutputSink(opts, outputPath)
+	if err != nil {
+		return err
+	}
+	if outFile != nil {
+		defer outFile.Close()
+	}
+
+	for {
+		fmt.Fprintln(opts.IO.StdOut)
 
-		err = prompt.AskOne(promptOpts, &selectedJob)
+		summary, err := ciutils.RunTrace(ctx, apiClient, sink, repo.FullName(), job, "")
 		if err != nil {
-			if errors.Is(err, terminal.InterruptErr) {
-				return nil
+			return err
+		}
+
+		if opts.Bundle != "" {
+			if err := writeBundleExtras(apiClient, repo, opts, job); err != nil {
+				return err
 			}
+		}
+
+		if summary.ExitCode != 0 && !opts.Follow {
+			return fmt.Errorf("job %q did not succeed: %s", job.Name, summary.Status)
+		}
 
+		if !opts.Follow {
+			return nil
+		}
+
+		job, err = waitForNextJob(ctx, opts, apiClient, repo, job)
+		if err != nil {
 			return err
 		}
+	}
+}
+
+// selectJobInteractively runs the original interactive survey flow: pick a
+// job from the pipeline, falling back to the first job, or to showing
+// commit statuses for external jobs when the pipeline has no GitLab jobs.
+// It sets opts.JobID, leaving it at 0 if there is nothing to trace.
+func selectJobInteractively(opts *TraceOpts, apiClient *gitlab.Client, repo glrepo.Interface, pipeline *gitlab.PipelineInfo, jobs []*gitlab.Job) error {
+	var jobOptions []string
+	var selectedJob string
+
+	for _, job := range jobs {
+		jobOptions = append(jobOptions, fmt.Sprintf("%s (%d) - %s", job.Name, job.ID, job.Status))
+	}
+
+	promptOpts := &survey.Select{
+		Message: "Select pipeline job to trace:",
+		Options: jobOptions,
+	}
+
+	err := prompt.AskOne(promptOpts, &selectedJob)
+	if err != nil {
+		if errors.Is(err, terminal.InterruptErr) {
+			return nil
+		}
 
-		if selectedJob != "" {
-			re := regexp.MustCompile(`(?s)\((.*)\)`)
-			m := re.FindAllStringSubmatch(selectedJob, -1)
-			opts.JobID = utils.StringToInt(m[0][1])
-		} else if len(jobs) > 0 {
-			opts.JobID = jobs[0].ID
-		} else {
-			// use commit statuses to show external jobs
-			cs, err := api.GetCommitStatuses(apiClient, repo.FullName(), pipeline.SHA)
-			if err != nil {
-				return nil
-			}
+		return err
+	}
 
-			c := opts.IO.Color()
+	if selectedJob != "" {
+		re := regexp.MustCompile(`(?s)\((.*)\)`)
+		m := re.FindAllStringSubmatch(selectedJob, -1)
+		opts.JobID = utils.StringToInt(m[0][1])
+		return nil
+	}
 
-			fmt.Fprint(opts.IO.StdOut, "Getting external jobs...")
-			for _, status := range cs {
-				var s string
+	if len(jobs) > 0 {
+		opts.JobID = jobs[0].ID
+		return nil
+	}
 
-				switch status.Status {
-				case "success":
-					s = c.Green(status.Status)
-				case "error":
-					s = c.Red(status.Status)
-				default:
-					s = c.Gray(status.Status)
-				}
-				fmt.Fprintf(opts.IO.StdOut, "(%s) %s\nURL: %s\n\n", s, c.Bold(status.Name), c.Gray(status.TargetURL))
+	// use commit statuses to show external jobs
+	cs, err := api.GetCommitStatuses(apiClient, repo.FullName(), pipeline.SHA)
+	if err != nil {
+		return nil
+	}
+
+	c := opts.IO.Color()
+
+	fmt.Fprint(opts.IO.StdOut, "Getting external jobs...")
+	for _, status := range cs {
+		var s string
+
+		switch status.Status {
+		case "success":
+			s = c.Green(status.Status)
+		case "error":
+			s = c.Red(status.Status)
+		default:
+			s = c.Gray(status.Status)
+		}
+		fmt.Fprintf(opts.IO.StdOut, "(%s) %s\nURL: %s\n\n", s, c.Bold(status.Name), c.Gray(status.TargetURL))
+	}
+
+	return nil
+}
+
+// waitForNextJob polls for a new pipeline on opts.Branch and resolves the
+// job in it with the same name as previousJob, for --follow mode. It blocks
+// until a new pipeline appears, opts.MaxWait elapses, or ctx is canceled.
+func waitForNextJob(ctx context.Context, opts *TraceOpts, apiClient *gitlab.Client, repo glrepo.Interface, previousJob *gitlab.Job) (*gitlab.Job, error) {
+	fmt.Fprintf(opts.IO.StdOut, "\nWaiting for a new pipeline on %s...\n", opts.Branch)
+
+	previousSHA := previousJob.Pipeline.SHA
+	jobName := previousJob.Name
+
+	var deadline <-chan time.Time
+	if opts.MaxWait > 0 {
+		timer := time.NewTimer(opts.MaxWait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, fmt.Errorf("timed out after %s waiting for a new pipeline on %s", opts.MaxWait, opts.Branch)
+		case <-ticker.C:
+		}
+
+		pipeline, err := api.GetLastPipeline(apiClient, repo.FullName(), opts.Branch)
+		if err != nil {
+			return nil, err
+		}
+		if pipeline.SHA == previousSHA {
+			continue
+		}
+
+		jobs, err := api.GetPipelineJobs(apiClient, pipeline.ID, repo.FullName())
+		if err != nil {
+			return nil, err
+		}
+
+		var matches []*gitlab.Job
+		for _, j := range jobs {
+			if j.Name == jobName {
+				matches = append(matches, j)
 			}
+		}
 
-			return nil
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("no job named %q found in pipeline %d", jobName, pipeline.ID)
+		case 1:
+			return matches[0], nil
+		default:
+			return selectJobByName(matches)
 		}
 	}
+}
 
-	job, err := api.GetPipelineJob(apiClient, opts.JobID, repo.FullName())
+// selectJobByName lets the user pick between several jobs that share a
+// name (e.g. retried or parallel/matrix jobs), since --follow can't tell
+// which one is "the" equivalent job on its own.
+func selectJobByName(jobs []*gitlab.Job) (*gitlab.Job, error) {
+	return promptSelectJob(jobs, "Multiple matching jobs found, select one to trace:")
+}
+
+// promptSelectJob asks the user to pick one of jobs with the given prompt
+// message, used both by --follow (selectJobByName) and by the
+// --job-name/--status predicate selection when it matches more than one job.
+func promptSelectJob(jobs []*gitlab.Job, message string) (*gitlab.Job, error) {
+	var jobOptions []string
+	for _, job := range jobs {
+		jobOptions = append(jobOptions, fmt.Sprintf("%s (%d) - %s", job.Name, job.ID, job.Status))
+	}
+
+	var selectedJob string
+	err := prompt.AskOne(&survey.Select{
+		Message: message,
+		Options: jobOptions,
+	}, &selectedJob)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, opt := range jobOptions {
+		if opt == selectedJob {
+			return jobs[i], nil
+		}
+	}
+
+	return nil, errors.New("no job selected")
+}
+
+// multiTraceRun fans out over every job selected by opts.All, opts.Stage,
+// opts.Match, or opts.JobNames, streaming all of them concurrently into a
+// shared, mutex-protected sink, each line tagged with its job name.
+func multiTraceRun(ctx context.Context, opts *TraceOpts, apiClient *gitlab.Client, repo glrepo.Interface) error {
+	pipelineID := opts.Pipeline
+	if pipelineID < 1 {
+		fmt.Fprintf(opts.IO.StdOut, "\nSearching for latest pipeline on %s...\n", opts.Branch)
+
+		pipeline, err := api.GetLastPipeline(apiClient, repo.FullName(), opts.Branch)
+		if err != nil {
+			return err
+		}
+		pipelineID = pipeline.ID
+	}
+
+	jobs, err := api.GetPipelineJobs(apiClient, pipelineID, repo.FullName())
+	if err != nil {
+		return err
+	}
+
+	matched, err := selectJobs(jobs, opts)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(opts.IO.StdOut)
+	if len(matched) == 0 {
+		return fmt.Errorf("no jobs in pipeline %d matched the given selection", pipelineID)
+	}
 
-	err = ciutils.RunTrace(context.Background(), apiClient, opts.IO.StdOut, repo.FullName(), job, job.Name)
+	base, outFile, err := newOutputSink(opts, opts.Output)
 	if err != nil {
 		return err
 	}
+	if outFile != nil {
+		defer outFile.Close()
+	}
+	sink := ciutils.NewSyncSink(base)
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultTraceConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	var failures int32
+
+	for _, job := range matched {
+		job := job
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			summary, err := ciutils.RunTrace(ctx, apiClient, sink, repo.FullName(), job, job.Name)
+			if err != nil || summary.ExitCode != 0 {
+				atomic.AddInt32(&failures, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d traced job(s) did not succeed", failures, len(matched))
+	}
 
 	return nil
 }
+
+// selectJobs filters jobs down to the ones requested by opts: every job
+// (--all), every job in a stage (--stage), every job whose name matches a
+// regular expression (--match), or an explicit list of job names.
+func selectJobs(jobs []*gitlab.Job, opts *TraceOpts) ([]*gitlab.Job, error) {
+	var matchRE *regexp.Regexp
+	if opts.Match != "" {
+		re, err := regexp.Compile(opts.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --match pattern: %w", err)
+		}
+		matchRE = re
+	}
+
+	names := make(map[string]bool, len(opts.JobNames))
+	for _, n := range opts.JobNames {
+		names[n] = true
+	}
+
+	var matched []*gitlab.Job
+	for _, job := range jobs {
+		switch {
+		case opts.All:
+			matched = append(matched, job)
+		case opts.Stage != "" && job.Stage == opts.Stage:
+			matched = append(matched, job)
+		case matchRE != nil && matchRE.MatchString(job.Name):
+			matched = append(matched, job)
+		case names[job.Name]:
+			matched = append(matched, job)
+		}
+	}
+
+	return matched, nil
+}
@@ -0,0 +1,55 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/profclems/glab/commands/ci/ciutils"
+)
+
+func TestValidateMultiTraceFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    TraceOpts
+		wantErr bool
+	}{
+		{
+			name: "single job, json format is fine",
+			opts: TraceOpts{Format: string(ciutils.FormatJSON)},
+		},
+		{
+			name: "multi-job, no conflicting flags",
+			opts: TraceOpts{All: true, Format: string(ciutils.FormatText)},
+		},
+		{
+			name: "multi-job, ndjson format is fine",
+			opts: TraceOpts{All: true, Format: string(ciutils.FormatNDJSON)},
+		},
+		{
+			name:    "multi-job with --bundle",
+			opts:    TraceOpts{Stage: "test", Bundle: "./out"},
+			wantErr: true,
+		},
+		{
+			name:    "multi-job with --follow",
+			opts:    TraceOpts{Match: ".*", Follow: true},
+			wantErr: true,
+		},
+		{
+			name:    "multi-job with --format=json",
+			opts:    TraceOpts{JobNames: []string{"a", "b"}, Format: string(ciutils.FormatJSON)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMultiTraceFlags(&tt.opts)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}